@@ -18,10 +18,10 @@
 // This is the list of the currently implemented blending modes:
 //
 // Add, Color, Color Burn, Color Dodge, Darken, Darker Color, Difference,
-// Divide, Exclusion, Hard Light, Hard Mix, Hue, Lighten, Lighter Color,
-// Linear Burn, Linear Dodge, Linear Light, Luminosity, Multiply, Overlay,
-// Phoenix, Pin Light, Reflex, Saturation, Screen, Soft Light, Substract,
-// Vivid Light.
+// Dissolve, Divide, Exclusion, Hard Light, Hard Mix, Hue, Lighten,
+// Lighter Color, Linear Burn, Linear Dodge, Linear Light, Luminosity,
+// Multiply, Overlay, Phoenix, Pin Light, Reflex, Saturation, Screen,
+// Soft Light, Soft Light (Pegtop), Substract, Vivid Light.
 //
 // Check github for more details:
 // http://github.com/phrozen/blend
@@ -51,34 +51,36 @@ type BlendFunc func(dst, src color.Color) color.Color
 
 // Available Moodes map
 var Modes = map[string]BlendFunc{
-	"add":           Add,
-	"color":         Color,
-	"color_burn":    ColorBurn,
-	"color_dodge":   ColorDodge,
-	"darken":        Darken,
-	"darker_color":  DarkerColor,
-	"difference":    Difference,
-	"divide":        Divide,
-	"exclusion":     Exclusion,
-	"hard_light":    HardLight,
-	"hard_mix":      HardMix,
-	"hue":           Hue,
-	"lighten":       Lighten,
-	"lighter_color": LighterColor,
-	"linear_burn":   LinearBurn,
-	"linear_dodge":  LinearDodge,
-	"linear_light":  LinearLight,
-	"luminosity":    Luminosity,
-	"multiply":      Multiply,
-	"overlay":       Overlay,
-	"phoenix":       Phoenix,
-	"pin_light":     PinLight,
-	"reflex":        Reflex,
-	"saturation":    Saturation,
-	"screen":        Screen,
-	"soft_light":    SoftLight,
-	"substract":     Substract,
-	"vivid_light":   VividLight,
+	"add":               Add,
+	"color":             Color,
+	"color_burn":        ColorBurn,
+	"color_dodge":       ColorDodge,
+	"darken":            Darken,
+	"darker_color":      DarkerColor,
+	"difference":        Difference,
+	"dissolve":          Dissolve,
+	"divide":            Divide,
+	"exclusion":         Exclusion,
+	"hard_light":        HardLight,
+	"hard_mix":          HardMix,
+	"hue":               Hue,
+	"lighten":           Lighten,
+	"lighter_color":     LighterColor,
+	"linear_burn":       LinearBurn,
+	"linear_dodge":      LinearDodge,
+	"linear_light":      LinearLight,
+	"luminosity":        Luminosity,
+	"multiply":          Multiply,
+	"overlay":           Overlay,
+	"phoenix":           Phoenix,
+	"pin_light":         PinLight,
+	"reflex":            Reflex,
+	"saturation":        Saturation,
+	"screen":            Screen,
+	"soft_light":        SoftLight,
+	"soft_light_pegtop": SoftLightPegtop,
+	"substract":         Substract,
+	"vivid_light":       VividLight,
 }
 
 // BlendImage blends src image (top layer) into dst image (bottom layer) using
@@ -86,14 +88,7 @@ var Modes = map[string]BlendFunc{
 // where the src image overlaps the dst image and the result is stored
 // in the original dst image, src image is unmutable.
 func BlendImage(dst draw.Image, src image.Image, mode BlendFunc) {
-	// Obtain the intersection of both images.
-	inter := dst.Bounds().Intersect(src.Bounds())
-	// Apply BlendFuc to each pixel in the intersection.
-	for y := inter.Min.Y; y < inter.Max.Y; y++ {
-		for x := inter.Min.X; x < inter.Max.X; x++ {
-			dst.Set(x, y, mode(dst.At(x, y), src.At(x, y)))
-		}
-	}
+	blendImage(dst, src, mode, BlendOptions{NumWorkers: 1})
 }
 
 // BlendNewImage blends src image (top layer) into dst image (bottom layer) using
@@ -101,24 +96,7 @@ func BlendImage(dst draw.Image, src image.Image, mode BlendFunc) {
 // where the src image overlaps the dst image and returns the resulting
 // image without modifying src, or dst as they are both unmutable.
 func BlendNewImage(dst, src image.Image, mode BlendFunc) image.Image {
-	// Obtain the intersection of both images.
-	inter := dst.Bounds().Intersect(src.Bounds())
-	// Create a new RGBA or RGBA64 image to return the values.
-	img := image.NewRGBA(dst.Bounds())
-	// Iterate over dst image pixels.
-	for y := dst.Bounds().Min.Y; y < dst.Bounds().Max.Y; y++ {
-		for x := dst.Bounds().Min.X; x < dst.Bounds().Max.X; x++ {
-			// If src is inside the intersection, we blend both
-			// pixels using the provided BlendFunc (mode).
-			if p := image.Pt(x, y); p.In(inter) {
-				img.Set(x, y, mode(dst.At(x, y), src.At(x, y)))
-			} else {
-				// Else we copy dst pixel to the resulting image.
-				img.Set(x, y, dst.At(x, y))
-			}
-		}
-	}
-	return img
+	return blendNewImage(dst, src, mode, BlendOptions{NumWorkers: 1})
 }
 
 func blendPerChannel(dst, src color.Color, bf func(float64, float64) float64) color.Color {
@@ -330,42 +308,22 @@ func Divide(dst, src color.Color) color.Color {
 	return blendPerChannel(dst, src, divide)
 }
 func divide(d, s float64) float64 {
-	return (d*max)/s + 1.0
-}
-
-// Blending modes that use HSL color model transformations.
-/*-------------------------------------------------------*/
-
-// Hue ...
-func Hue(dst, src color.Color) color.Color {
-	s := rgb2hsl(src)
-	if s.s == 0.0 {
-		return dst
+	if s == 0.0 {
+		return max
 	}
-	d := rgb2hsl(dst)
-	return hsl2rgb(s.h, d.s, d.l)
+	return math.Min(max, d*max/s)
 }
 
-// Saturation ...
-func Saturation(dst, src color.Color) color.Color {
-	s := rgb2hsl(src)
-	d := rgb2hsl(dst)
-	return hsl2rgb(d.h, s.s, d.l)
+// SoftLightPegtop ...
+func SoftLightPegtop(dst, src color.Color) color.Color {
+	return blendPerChannel(dst, src, softLightPegtop)
 }
-
-// Color ...
-func Color(dst, src color.Color) color.Color {
-	s := rgb2hsl(src)
-	d := rgb2hsl(dst)
-	return hsl2rgb(s.h, s.s, d.l)
+func softLightPegtop(d, s float64) float64 {
+	return (1-2*s/max)*d*d/max + 2*s*d/max
 }
 
-// Luminosity ...
-func Luminosity(dst, src color.Color) color.Color {
-	s := rgb2hsl(src)
-	d := rgb2hsl(dst)
-	return hsl2rgb(d.h, d.s, s.l)
-}
+// Hue, Saturation, Color and Luminosity are defined in nonseparable.go;
+// their pre-PDF-spec HSL counterparts LegacyHue, etc. are in hsl.go.
 
 // This blending modes are not implemented in Photoshop
 // or GIMP at the moment, but produced their desired results.
@@ -21,11 +21,3 @@ func color2rgbaf64(c color.Color) rgbaf64 {
 	r, g, b, a := c.RGBA()
 	return rgbaf64{float64(r), float64(g), float64(b), float64(a)}
 }
-
-func HSLtoRGB() {
-	
-}
-
-func RGBtoHSL() {
-	
-}
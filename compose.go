@@ -0,0 +1,103 @@
+// Copyright (c) 2012 Guillermo Estrada. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package blend
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Composited wraps a BlendFunc so that its result is alpha-composited over
+// dst using the Porter-Duff "over" formula from PDF 1.7 §7.2.5, instead of
+// simply inheriting dst's alpha as the built-in modes do:
+//
+//	Cr = (1-as)*ab*Cb + (1-ab)*as*Cs + ab*as*B(Cb,Cs)
+//	ar = ab + as - ab*as
+//
+// where B is the wrapped BlendFunc, Cb/ab are the dst color/alpha and
+// Cs/as are the src color/alpha. Wrapping a mode with Composited is what
+// makes a partially transparent src paint as partially transparent instead
+// of fully opaque.
+//
+// The formula is defined in terms of straight (un-premultiplied) colors,
+// but color.Color.RGBA() — and every channel this package reads — is
+// already alpha-premultiplied, so dst/src are unpremultiplied before mode
+// is evaluated and before the formula is applied; otherwise ab/as would
+// be folded into Cb/Cs twice.
+func Composited(mode BlendFunc) BlendFunc {
+	return func(dst, src color.Color) color.Color {
+		d, s := color2rgbaf64(dst), color2rgbaf64(src)
+		ab, as := d.a/max, s.a/max
+		straightD, straightS := unpremultiply(d, ab), unpremultiply(s, as)
+		b := color2rgbaf64(mode(straightD, straightS))
+		return porterDuffOver(straightD, straightS, b, ab, as)
+	}
+}
+
+// unpremultiply divides c's color channels by alpha, recovering the
+// straight color that was premultiplied into them. c.a is passed in
+// separately because it has typically already been normalized to [0, 1]
+// by the caller.
+func unpremultiply(c rgbaf64, alpha float64) rgbaf64 {
+	if alpha == 0 {
+		return rgbaf64{0, 0, 0, c.a}
+	}
+	return rgbaf64{c.r / alpha, c.g / alpha, c.b / alpha, c.a}
+}
+
+// porterDuffOver composites the straight colors d, s and the already
+// straight blend result b using alpha fractions ab, as, per the
+// Porter-Duff "over" formula. Because the formula's coefficients sum to
+// ar = ab + as - ab*as rather than 1, the result is the straight output
+// color already multiplied by ar, i.e. directly the premultiplied output
+// color — no further premultiplication needed.
+func porterDuffOver(d, s, b rgbaf64, ab, as float64) rgbaf64 {
+	over := func(cb, cs, cv float64) float64 {
+		return (1-as)*ab*cb + (1-ab)*as*cs + ab*as*cv
+	}
+	return rgbaf64{
+		over(d.r, s.r, b.r),
+		over(d.g, s.g, b.g),
+		over(d.b, s.b, b.b),
+		(ab + as - ab*as) * max,
+	}
+}
+
+// BlendImageWithOpacity blends src into dst using mode, first scaling src's
+// alpha channel by opacity (clamped to [0,1]) and then compositing the
+// result over dst with Composited, mimicking a layer opacity slider.
+func BlendImageWithOpacity(dst draw.Image, src image.Image, mode BlendFunc, opacity float64) {
+	blendImage(dst, opacitySource{src, clampOpacity(opacity)}, Composited(mode), BlendOptions{NumWorkers: 1})
+}
+
+func clampOpacity(opacity float64) float64 {
+	if opacity < 0 {
+		return 0
+	}
+	if opacity > 1 {
+		return 1
+	}
+	return opacity
+}
+
+// opacitySource wraps an image.Image and scales every pixel's alpha by a
+// fixed opacity factor as it is read.
+type opacitySource struct {
+	image.Image
+	opacity float64
+}
+
+func (o opacitySource) At(x, y int) color.Color {
+	return scaleAlpha(color2rgbaf64(o.Image.At(x, y)), o.opacity)
+}
+
+// scaleAlpha scales a premultiplied color's alpha by factor. Since
+// premultiplied channels are straight-color * alpha, uniformly scaling
+// alpha by factor requires scaling r/g/b by the same factor to keep the
+// straight color they represent unchanged.
+func scaleAlpha(c rgbaf64, factor float64) rgbaf64 {
+	return rgbaf64{c.r * factor, c.g * factor, c.b * factor, c.a * factor}
+}
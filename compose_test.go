@@ -0,0 +1,55 @@
+package blend
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// passThrough is a BlendFunc that performs no blending at all, so any
+// compositing math applied around it can be checked in isolation.
+func passThrough(dst, src color.Color) color.Color {
+	return src
+}
+
+// TestCompositedPartialAlpha is a regression test for a bug where
+// Composited folded ab/as into Cb/Cs twice, because d/s/b were never
+// unpremultiplied before the Porter-Duff formula was applied. 50%-alpha
+// white over opaque black should land near mid-gray and fully opaque, not
+// at a quarter of that.
+func TestCompositedPartialAlpha(t *testing.T) {
+	dst := color.RGBA{0, 0, 0, 255}
+	src := color.NRGBA{255, 255, 255, 128}
+
+	r, g, b, a := Composited(passThrough)(dst, src).RGBA()
+
+	const want, tol = 0.5 * max, max / 200
+	if math.Abs(float64(r)-want) > tol || math.Abs(float64(g)-want) > tol || math.Abs(float64(b)-want) > tol {
+		t.Fatalf("Composited(passThrough)(dst, src) = (%d,%d,%d,%d), want ~(%.0f,%.0f,%.0f,%d)",
+			r, g, b, a, want, want, want, uint32(max))
+	}
+	if a != max {
+		t.Fatalf("result alpha = %d, want %d (opaque dst behind any src alpha composites opaque)", a, uint32(max))
+	}
+}
+
+// TestBlendImageWithOpacity checks that scaling src's alpha by opacity
+// (via opacitySource) produces the same straight color at half the
+// alpha, not a color that's also gone half as bright.
+func TestBlendImageWithOpacity(t *testing.T) {
+	bounds := image.Rect(0, 0, 1, 1)
+	dst := image.NewRGBA(bounds)
+	dst.Set(0, 0, color.RGBA{0, 0, 0, 255})
+	src := image.NewRGBA(bounds)
+	src.Set(0, 0, color.RGBA{255, 255, 255, 255})
+
+	BlendImageWithOpacity(dst, src, passThrough, 0.5)
+
+	r, g, b, a := dst.At(0, 0).RGBA()
+	const want, tol = 0.5 * max, max / 200
+	if math.Abs(float64(r)-want) > tol || math.Abs(float64(g)-want) > tol || math.Abs(float64(b)-want) > tol {
+		t.Fatalf("BlendImageWithOpacity(opacity=0.5) = (%d,%d,%d,%d), want ~(%.0f,%.0f,%.0f,%d)",
+			r, g, b, a, want, want, want, uint32(max))
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2012 Guillermo Estrada. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package blend
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math/rand"
+	"sync"
+)
+
+// PositionalBlendFunc is like BlendFunc but also receives the position of
+// the pixel being blended. Dissolve needs it because, unlike every other
+// mode, its output is stochastic and a BlendFunc alone has nowhere to
+// carry a seeded source of randomness across calls.
+type PositionalBlendFunc func(x, y int, dst, src color.Color) color.Color
+
+// BlendImagePositional blends src image (top layer) into dst image
+// (bottom layer) using the PositionalBlendFunc provided by mode, the same
+// way BlendImage does for a plain BlendFunc.
+func BlendImagePositional(dst draw.Image, src image.Image, mode PositionalBlendFunc) {
+	inter := dst.Bounds().Intersect(src.Bounds())
+	for y := inter.Min.Y; y < inter.Max.Y; y++ {
+		for x := inter.Min.X; x < inter.Max.X; x++ {
+			dst.Set(x, y, mode(x, y, dst.At(x, y), src.At(x, y)))
+		}
+	}
+}
+
+// NewDissolve returns a PositionalBlendFunc implementing the Dissolve
+// mode, seeded with seed so that the same seed driven through
+// BlendImagePositional always produces the same output. For each pixel it
+// draws a uniform float in [0, 1) from a *rand.Rand and outputs src when
+// the draw is less than src's normalized alpha, dst otherwise.
+func NewDissolve(seed int64) PositionalBlendFunc {
+	rng := rand.New(rand.NewSource(seed))
+	return func(x, y int, dst, src color.Color) color.Color {
+		s := color2rgbaf64(src)
+		if rng.Float64() < s.a/max {
+			return src
+		}
+		return dst
+	}
+}
+
+// dissolveRNG backs the default-seeded Dissolve BlendFunc below. Dissolve
+// is reachable from BlendImageParallel and Stack.RenderParallel, both of
+// which call it from multiple goroutines at once, so access to the shared
+// *rand.Rand is serialized with dissolveMu.
+var (
+	dissolveMu  sync.Mutex
+	dissolveRNG = rand.New(rand.NewSource(1))
+)
+
+// Dissolve is the default-seeded BlendFunc form of the Dissolve mode,
+// registered in Modes for convenience. It shares a single *rand.Rand
+// across all calls, so callers that need reproducible output, or that
+// want to avoid the mutex contention of the shared source under heavy
+// parallel use, should use NewDissolve with BlendImagePositional instead.
+func Dissolve(dst, src color.Color) color.Color {
+	s := color2rgbaf64(src)
+	dissolveMu.Lock()
+	roll := dissolveRNG.Float64()
+	dissolveMu.Unlock()
+	if roll < s.a/max {
+		return src
+	}
+	return dst
+}
@@ -0,0 +1,33 @@
+package blend
+
+import (
+	"image/color"
+	"testing"
+)
+
+// TestDivide is a regression test for the historical Divide bug, where
+// divide computed (d*max)/s+1.0 and overflowed past max instead of
+// clamping. The reference outputs below were computed with the fixed
+// formula, min(max, d*max/s), guarded against s == 0.
+func TestDivide(t *testing.T) {
+	cases := []struct {
+		dst, src   color.Color
+		r, g, b, a uint32
+	}{
+		// black / white: 0 / anything clamps to 0.
+		{color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255}, 0, 0, 0, 65535},
+		// white / white: divides to exactly max.
+		{color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, 65535, 65535, 65535, 65535},
+		// dst twice src: would overflow past max under the old formula.
+		{color.RGBA{128, 128, 128, 255}, color.RGBA{64, 64, 64, 255}, 65535, 65535, 65535, 65535},
+		// src == 0: must not divide by zero.
+		{color.RGBA{0, 0, 0, 255}, color.RGBA{0, 0, 0, 255}, 65535, 65535, 65535, 65535},
+	}
+	for i, c := range cases {
+		r, g, b, a := Divide(c.dst, c.src).RGBA()
+		if r != c.r || g != c.g || b != c.b || a != c.a {
+			t.Errorf("case %d: Divide(%v, %v) = (%d,%d,%d,%d), want (%d,%d,%d,%d)",
+				i, c.dst, c.src, r, g, b, a, c.r, c.g, c.b, c.a)
+		}
+	}
+}
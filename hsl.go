@@ -0,0 +1,104 @@
+// Copyright (c) 2012 Guillermo Estrada. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package blend
+
+import (
+	"image/color"
+	"math"
+)
+
+// hslColor is an HSL representation with h in [0, 360) and s, l in [0, 1].
+type hslColor struct {
+	h, s, l float64
+}
+
+// rgb2hsl converts a color.Color to the HSL color model.
+func rgb2hsl(c color.Color) hslColor {
+	cf := color2rgbaf64(c)
+	r, g, b := cf.r/max, cf.g/max, cf.b/max
+	mx := math.Max(r, math.Max(g, b))
+	mn := math.Min(r, math.Min(g, b))
+	l := (mx + mn) / 2.0
+	if mx == mn {
+		return hslColor{0.0, 0.0, l}
+	}
+	d := mx - mn
+	s := d / (1 - math.Abs(2*l-1))
+	var h float64
+	switch mx {
+	case r:
+		h = math.Mod((g-b)/d, 6.0)
+	case g:
+		h = (b-r)/d + 2.0
+	default:
+		h = (r-g)/d + 4.0
+	}
+	h *= 60.0
+	if h < 0 {
+		h += 360.0
+	}
+	return hslColor{h, s, l}
+}
+
+// hsl2rgb converts an HSL color back to a color.Color with a = max.
+func hsl2rgb(h, s, l float64) color.Color {
+	if s == 0.0 {
+		v := l * max
+		return rgbaf64{v, v, v, max}
+	}
+	c := (1 - math.Abs(2*l-1)) * s
+	hp := h / 60.0
+	x := c * (1 - math.Abs(math.Mod(hp, 2)-1))
+	var r, g, b float64
+	switch {
+	case hp < 1:
+		r, g, b = c, x, 0
+	case hp < 2:
+		r, g, b = x, c, 0
+	case hp < 3:
+		r, g, b = 0, c, x
+	case hp < 4:
+		r, g, b = 0, x, c
+	case hp < 5:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	m := l - c/2.0
+	return rgbaf64{(r + m) * max, (g + m) * max, (b + m) * max, max}
+}
+
+// LegacyHue is the pre-PDF-spec implementation of Hue that converts
+// through the HSL color model, kept for callers that depended on its
+// behavior before Hue was switched to the PDF 1.7 §11.3.5.3 formula.
+func LegacyHue(dst, src color.Color) color.Color {
+	s := rgb2hsl(src)
+	if s.s == 0.0 {
+		return dst
+	}
+	d := rgb2hsl(dst)
+	return hsl2rgb(s.h, d.s, d.l)
+}
+
+// LegacySaturation is the pre-PDF-spec implementation of Saturation.
+func LegacySaturation(dst, src color.Color) color.Color {
+	s := rgb2hsl(src)
+	d := rgb2hsl(dst)
+	return hsl2rgb(d.h, s.s, d.l)
+}
+
+// LegacyColor is the pre-PDF-spec implementation of Color.
+func LegacyColor(dst, src color.Color) color.Color {
+	s := rgb2hsl(src)
+	d := rgb2hsl(dst)
+	return hsl2rgb(s.h, s.s, d.l)
+}
+
+// LegacyLuminosity is the pre-PDF-spec implementation of Luminosity.
+func LegacyLuminosity(dst, src color.Color) color.Color {
+	s := rgb2hsl(src)
+	d := rgb2hsl(dst)
+	return hsl2rgb(d.h, d.s, s.l)
+}
@@ -0,0 +1,239 @@
+// Copyright (c) 2012 Guillermo Estrada. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package blend
+
+import (
+	"image"
+	"reflect"
+)
+
+// pixKernel blends src into dst in place over [x0,x1)x[y0,y1), reading and
+// writing their Pix slices directly with the given stride instead of
+// going through color.Color. Coordinates are relative to each slice's own
+// Pix buffer (i.e. already adjusted for image.Bounds().Min), which is why
+// pixKernel is only handed out for a matching pair of same-bounds,
+// same-type images; see pixBuffers.
+type pixKernel func(dst, src []byte, stride, x0, y0, x1, y1 int)
+
+// rgbaBlender reads and writes a single pixel's channels as float64
+// values in [0, max] for one of the four Pix layouts blend knows how to
+// fast-path. Having one implementation per layout lets channelKernel
+// build a pixKernel for any per-channel BlendFunc once, instead of every
+// mode hand-writing its own byte-level code four times over.
+type rgbaBlender interface {
+	bytesPerPixel() int
+	get(pix []byte, i int) (r, g, b, a float64)
+	set(pix []byte, i int, r, g, b, a float64)
+}
+
+// channelKernel builds a pixKernel that applies a per-channel blend
+// function fn (the same shape used by blendPerChannel) directly over
+// blender's Pix layout, preserving dst's alpha like blendPerChannel does.
+func channelKernel(fn func(d, s float64) float64, blender rgbaBlender) pixKernel {
+	bpp := blender.bytesPerPixel()
+	return func(dst, src []byte, stride, x0, y0, x1, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := y * stride
+			for x := x0; x < x1; x++ {
+				i := row + x*bpp
+				dr, dg, db, da := blender.get(dst, i)
+				sr, sg, sb, _ := blender.get(src, i)
+				blender.set(dst, i, fn(dr, sr), fn(dg, sg), fn(db, sb), da)
+			}
+		}
+	}
+}
+
+type rgba8Blender struct{}
+
+func (rgba8Blender) bytesPerPixel() int { return 4 }
+func (rgba8Blender) get(pix []byte, i int) (r, g, b, a float64) {
+	return float64(pix[i]) * 257, float64(pix[i+1]) * 257, float64(pix[i+2]) * 257, float64(pix[i+3]) * 257
+}
+func (rgba8Blender) set(pix []byte, i int, r, g, b, a float64) {
+	pix[i] = uint8(float64ToUint16(r) >> 8)
+	pix[i+1] = uint8(float64ToUint16(g) >> 8)
+	pix[i+2] = uint8(float64ToUint16(b) >> 8)
+	pix[i+3] = uint8(float64ToUint16(a) >> 8)
+}
+
+// nrgba8Blender reads/writes the same byte layout as rgba8Blender, but
+// image.NRGBA stores straight (non-premultiplied) channels, so get/set
+// have to premultiply on the way in and unpremultiply on the way out to
+// agree with what color.NRGBA.RGBA() and its Model.Convert would produce.
+// Both do their rounding in integer arithmetic rather than float64, and
+// in a specific order (multiply-then-truncating-divide), so get/set
+// replicate that arithmetic exactly rather than an equivalent-looking
+// float64 expression: color.NRGBA.RGBA() truncates the premultiply
+// before any blend math runs, so carrying extra float64 precision
+// through the blend function and only rounding in set would let the
+// kernel's output drift from the generic color.Color path by a ULP.
+type nrgba8Blender struct{}
+
+func (nrgba8Blender) bytesPerPixel() int { return 4 }
+func (nrgba8Blender) get(pix []byte, i int) (r, g, b, a float64) {
+	a16 := uint32(pix[i+3]) * 257
+	r = float64(uint32(pix[i])*257*a16/0xffff)
+	g = float64(uint32(pix[i+1])*257*a16/0xffff)
+	b = float64(uint32(pix[i+2])*257*a16/0xffff)
+	a = float64(a16)
+	return
+}
+func (nrgba8Blender) set(pix []byte, i int, r, g, b, a float64) {
+	a16 := uint32(float64ToUint16(a))
+	switch a16 {
+	case 0xffff:
+		pix[i] = uint8(float64ToUint16(r) >> 8)
+		pix[i+1] = uint8(float64ToUint16(g) >> 8)
+		pix[i+2] = uint8(float64ToUint16(b) >> 8)
+		pix[i+3] = 0xff
+	case 0:
+		pix[i], pix[i+1], pix[i+2], pix[i+3] = 0, 0, 0, 0
+	default:
+		pix[i] = uint8((uint32(float64ToUint16(r)) * 0xffff / a16) >> 8)
+		pix[i+1] = uint8((uint32(float64ToUint16(g)) * 0xffff / a16) >> 8)
+		pix[i+2] = uint8((uint32(float64ToUint16(b)) * 0xffff / a16) >> 8)
+		pix[i+3] = uint8(a16 >> 8)
+	}
+}
+
+type rgba16Blender struct{}
+
+func (rgba16Blender) bytesPerPixel() int { return 8 }
+func (rgba16Blender) get(pix []byte, i int) (r, g, b, a float64) {
+	r = float64(uint16(pix[i])<<8 | uint16(pix[i+1]))
+	g = float64(uint16(pix[i+2])<<8 | uint16(pix[i+3]))
+	b = float64(uint16(pix[i+4])<<8 | uint16(pix[i+5]))
+	a = float64(uint16(pix[i+6])<<8 | uint16(pix[i+7]))
+	return
+}
+func (rgba16Blender) set(pix []byte, i int, r, g, b, a float64) {
+	putUint16(pix[i:], float64ToUint16(r))
+	putUint16(pix[i+2:], float64ToUint16(g))
+	putUint16(pix[i+4:], float64ToUint16(b))
+	putUint16(pix[i+6:], float64ToUint16(a))
+}
+
+// nrgba64Blender is the 16-bit-per-channel counterpart of nrgba8Blender,
+// replicating color.NRGBA64.RGBA()/Model.Convert's integer arithmetic the
+// same way and for the same reason.
+type nrgba64Blender struct{}
+
+func (nrgba64Blender) bytesPerPixel() int { return 8 }
+func (nrgba64Blender) get(pix []byte, i int) (r, g, b, a float64) {
+	R := uint32(pix[i])<<8 | uint32(pix[i+1])
+	G := uint32(pix[i+2])<<8 | uint32(pix[i+3])
+	B := uint32(pix[i+4])<<8 | uint32(pix[i+5])
+	a16 := uint32(pix[i+6])<<8 | uint32(pix[i+7])
+	r = float64(R * a16 / 0xffff)
+	g = float64(G * a16 / 0xffff)
+	b = float64(B * a16 / 0xffff)
+	a = float64(a16)
+	return
+}
+func (nrgba64Blender) set(pix []byte, i int, r, g, b, a float64) {
+	a16 := uint32(float64ToUint16(a))
+	switch a16 {
+	case 0xffff:
+		putUint16(pix[i:], float64ToUint16(r))
+		putUint16(pix[i+2:], float64ToUint16(g))
+		putUint16(pix[i+4:], float64ToUint16(b))
+		putUint16(pix[i+6:], 0xffff)
+	case 0:
+		putUint16(pix[i:], 0)
+		putUint16(pix[i+2:], 0)
+		putUint16(pix[i+4:], 0)
+		putUint16(pix[i+6:], 0)
+	default:
+		putUint16(pix[i:], uint16(uint32(float64ToUint16(r))*0xffff/a16))
+		putUint16(pix[i+2:], uint16(uint32(float64ToUint16(g))*0xffff/a16))
+		putUint16(pix[i+4:], uint16(uint32(float64ToUint16(b))*0xffff/a16))
+		putUint16(pix[i+6:], uint16(a16))
+	}
+}
+
+func putUint16(buf []byte, v uint16) {
+	buf[0] = byte(v >> 8)
+	buf[1] = byte(v)
+}
+
+// kernelFuncs maps the function pointer of a built-in BlendFunc to the
+// per-channel math it wraps, so blendRows can recover "multiply" etc. from
+// a mode value and hand it to channelKernel.
+var kernelFuncs = map[uintptr]func(d, s float64) float64{
+	funcPtr(Add):              add,
+	funcPtr(Multiply):         multiply,
+	funcPtr(ColorBurn):        colorBurn,
+	funcPtr(LinearBurn):       linearBurn,
+	funcPtr(Darken):           darken,
+	funcPtr(Lighten):          lighten,
+	funcPtr(Screen):           screen,
+	funcPtr(ColorDodge):       colorDodge,
+	funcPtr(LinearDodge):      linearDodge,
+	funcPtr(Overlay):          overlay,
+	funcPtr(SoftLight):        softLight,
+	funcPtr(SoftLightPegtop):  softLightPegtop,
+	funcPtr(HardLight):        hardLight,
+	funcPtr(VividLight):       vividLight,
+	funcPtr(LinearLight):      linearLight,
+	funcPtr(PinLight):         pinLight,
+	funcPtr(HardMix):          hardMix,
+	funcPtr(Difference):       difference,
+	funcPtr(Exclusion):        exclusion,
+	funcPtr(Substract):        substract,
+	funcPtr(Divide):           divide,
+	funcPtr(Reflex):           reflex,
+	funcPtr(Phoenix):          phoenix,
+}
+
+func funcPtr(fn BlendFunc) uintptr {
+	return reflect.ValueOf(fn).Pointer()
+}
+
+// pixBuffers returns the rgbaBlender and raw Pix slices to fast-path
+// blending dst/src directly, when they share the same concrete image type,
+// bounds and stride (so their Pix buffers line up pixel for pixel).
+func pixBuffers(dst, src image.Image) (rgbaBlender, []byte, []byte, int, bool) {
+	if dst.Bounds() != src.Bounds() {
+		return nil, nil, nil, 0, false
+	}
+	switch d := dst.(type) {
+	case *image.RGBA:
+		if s, ok := src.(*image.RGBA); ok && s.Stride == d.Stride {
+			return rgba8Blender{}, d.Pix, s.Pix, d.Stride, true
+		}
+	case *image.NRGBA:
+		if s, ok := src.(*image.NRGBA); ok && s.Stride == d.Stride {
+			return nrgba8Blender{}, d.Pix, s.Pix, d.Stride, true
+		}
+	case *image.RGBA64:
+		if s, ok := src.(*image.RGBA64); ok && s.Stride == d.Stride {
+			return rgba16Blender{}, d.Pix, s.Pix, d.Stride, true
+		}
+	case *image.NRGBA64:
+		if s, ok := src.(*image.NRGBA64); ok && s.Stride == d.Stride {
+			return nrgba64Blender{}, d.Pix, s.Pix, d.Stride, true
+		}
+	}
+	return nil, nil, nil, 0, false
+}
+
+// tryKernel attempts the byte-level fast path for [y0,y1) within inter,
+// returning false when mode has no registered kernel or dst/src don't
+// share a fast-pathable Pix layout, so the caller can fall back.
+func tryKernel(dst image.Image, src image.Image, mode BlendFunc, inter image.Rectangle, y0, y1 int) bool {
+	fn, ok := kernelFuncs[funcPtr(mode)]
+	if !ok {
+		return false
+	}
+	blender, dstPix, srcPix, stride, ok := pixBuffers(dst, src)
+	if !ok {
+		return false
+	}
+	min := dst.Bounds().Min
+	kernel := channelKernel(fn, blender)
+	kernel(dstPix, srcPix, stride, inter.Min.X-min.X, y0-min.Y, inter.Max.X-min.X, y1-min.Y)
+	return true
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2012 Guillermo Estrada. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package blend
+
+import (
+	"image/color"
+	"math"
+	"sort"
+)
+
+// Hue, Saturation, Color and Luminosity are the non-separable blend modes
+// defined in PDF 1.7 §11.3.5.3. Unlike the separable modes in blend.go, they
+// operate on all three channels at once through Lum/Sat, ClipColor,
+// SetLum and SetSat, which is what makes them match Photoshop/PDF output
+// where a naive RGB<->HSL round trip (see LegacyHue, etc.) does not.
+
+// Hue ...
+func Hue(dst, src color.Color) color.Color {
+	b, s := color2rgbaf64(dst), color2rgbaf64(src)
+	c := setLum(setSat(s, sat(b)), lum(b))
+	c.a = b.a
+	return c
+}
+
+// Saturation ...
+func Saturation(dst, src color.Color) color.Color {
+	b, s := color2rgbaf64(dst), color2rgbaf64(src)
+	c := setLum(setSat(b, sat(s)), lum(b))
+	c.a = b.a
+	return c
+}
+
+// Color ...
+func Color(dst, src color.Color) color.Color {
+	b, s := color2rgbaf64(dst), color2rgbaf64(src)
+	c := setLum(s, lum(b))
+	c.a = b.a
+	return c
+}
+
+// Luminosity ...
+func Luminosity(dst, src color.Color) color.Color {
+	b, s := color2rgbaf64(dst), color2rgbaf64(src)
+	c := setLum(b, lum(s))
+	c.a = b.a
+	return c
+}
+
+// lum returns the luminance of c, per PDF 1.7 §11.3.5.3.
+func lum(c rgbaf64) float64 {
+	return 0.3*c.r + 0.59*c.g + 0.11*c.b
+}
+
+// sat returns the saturation of c, per PDF 1.7 §11.3.5.3.
+func sat(c rgbaf64) float64 {
+	return math.Max(c.r, math.Max(c.g, c.b)) - math.Min(c.r, math.Min(c.g, c.b))
+}
+
+// clipColor brings an out-of-range color produced by setLum back into the
+// valid [0, max] range while preserving its luminance.
+func clipColor(c rgbaf64) rgbaf64 {
+	l := lum(c)
+	n := math.Min(c.r, math.Min(c.g, c.b))
+	x := math.Max(c.r, math.Max(c.g, c.b))
+	if n < 0.0 {
+		c.r = l + (c.r-l)*l/(l-n)
+		c.g = l + (c.g-l)*l/(l-n)
+		c.b = l + (c.b-l)*l/(l-n)
+	}
+	if x > max {
+		c.r = l + (c.r-l)*(max-l)/(x-l)
+		c.g = l + (c.g-l)*(max-l)/(x-l)
+		c.b = l + (c.b-l)*(max-l)/(x-l)
+	}
+	return c
+}
+
+// setLum adds l - Lum(c) to every channel of c, then clips it back into
+// range with clipColor.
+func setLum(c rgbaf64, l float64) rgbaf64 {
+	d := l - lum(c)
+	c.r += d
+	c.g += d
+	c.b += d
+	return clipColor(c)
+}
+
+// setSat adjusts c so that Sat(c) == s while preserving which channel is
+// smallest/largest, per PDF 1.7 §11.3.5.3.
+func setSat(c rgbaf64, s float64) rgbaf64 {
+	chans := [3]*float64{&c.r, &c.g, &c.b}
+	sort.Slice(chans[:], func(i, j int) bool { return *chans[i] < *chans[j] })
+	cmin, cmid, cmax := chans[0], chans[1], chans[2]
+	if *cmax > *cmin {
+		*cmid = (*cmid - *cmin) * s / (*cmax - *cmin)
+		*cmax = s
+	} else {
+		*cmid = 0.0
+		*cmax = 0.0
+	}
+	*cmin = 0.0
+	return c
+}
@@ -0,0 +1,100 @@
+package blend
+
+import (
+	"image/color"
+	"testing"
+)
+
+// When src has zero saturation (a gray), Hue and Saturation both reduce to
+// dst's luminance carried by an achromatic (r==g==b) result: Hue borrows
+// src's (undefined) hue onto a color with no saturation to show it, and
+// Saturation imposes src's zero saturation directly. Both also inherit
+// dst's alpha rather than src's, matching Color and Luminosity below.
+func TestHueSaturationAchromaticSrc(t *testing.T) {
+	dst := color.RGBA{200, 50, 10, 222}
+	src := color.RGBA{80, 80, 80, 111}
+	wantLum := uint32(23284) // 0.3*51400 + 0.59*12850 + 0.11*2570, rounded
+	wantAlpha := uint32(222) * 257
+
+	for _, tc := range []struct {
+		name string
+		fn   BlendFunc
+	}{
+		{"Hue", Hue},
+		{"Saturation", Saturation},
+	} {
+		r, g, b, a := tc.fn(dst, src).RGBA()
+		if r != g || g != b {
+			t.Errorf("%s(dst, grayscale src) = (%d,%d,%d), want achromatic (r==g==b)", tc.name, r, g, b)
+		}
+		if diff := int(r) - int(wantLum); diff < -1 || diff > 1 {
+			t.Errorf("%s(dst, grayscale src) luminance = %d, want ~%d", tc.name, r, wantLum)
+		}
+		if a != wantAlpha {
+			t.Errorf("%s(dst, grayscale src) alpha = %d, want dst's alpha %d", tc.name, a, wantAlpha)
+		}
+	}
+}
+
+// Color, symmetrically, reduces to dst's luminance when src is gray: with
+// no saturation to impose, the hue it borrows from src can't show either.
+func TestColorAchromaticSrc(t *testing.T) {
+	dst := color.RGBA{200, 50, 10, 222}
+	src := color.RGBA{80, 80, 80, 111}
+	wantLum := uint32(23284)
+	wantAlpha := uint32(222) * 257
+
+	r, g, b, a := Color(dst, src).RGBA()
+	if r != g || g != b {
+		t.Errorf("Color(dst, grayscale src) = (%d,%d,%d), want achromatic", r, g, b)
+	}
+	if diff := int(r) - int(wantLum); diff < -1 || diff > 1 {
+		t.Errorf("Color(dst, grayscale src) luminance = %d, want ~%d", r, wantLum)
+	}
+	if a != wantAlpha {
+		t.Errorf("Color(dst, grayscale src) alpha = %d, want dst's alpha %d", a, wantAlpha)
+	}
+}
+
+// Luminosity replaces dst's luminance with src's while keeping dst's hue
+// and saturation; with dst gray, that leaves an achromatic result at
+// src's luminance.
+func TestLuminosityAchromaticDst(t *testing.T) {
+	dst := color.RGBA{60, 60, 60, 222}
+	src := color.RGBA{200, 50, 10, 111}
+	wantLum := uint32(23284)
+	wantAlpha := uint32(222) * 257
+
+	r, g, b, a := Luminosity(dst, src).RGBA()
+	if r != g || g != b {
+		t.Errorf("Luminosity(grayscale dst, src) = (%d,%d,%d), want achromatic", r, g, b)
+	}
+	if diff := int(r) - int(wantLum); diff < -1 || diff > 1 {
+		t.Errorf("Luminosity(grayscale dst, src) luminance = %d, want ~%d", r, wantLum)
+	}
+	if a != wantAlpha {
+		t.Errorf("Luminosity(grayscale dst, src) alpha = %d, want dst's alpha %d", a, wantAlpha)
+	}
+}
+
+// TestHsl2RgbRoundTrip checks rgb2hsl/hsl2rgb round-trip a color back to
+// itself (mod the rounding hsl2rgb's final *max/float64ToUint16 pass
+// introduces), which the Legacy* modes rely on implicitly.
+func TestHsl2RgbRoundTrip(t *testing.T) {
+	want := color.RGBA{30, 144, 255, 255}
+	h := rgb2hsl(want)
+	r, g, b, _ := hsl2rgb(h.h, h.s, h.l).RGBA()
+
+	wr, wg, wb, _ := want.RGBA()
+	const tol = 257 // within one 8-bit step
+	if absDiff(r, wr) > tol || absDiff(g, wg) > tol || absDiff(b, wb) > tol {
+		t.Fatalf("hsl2rgb(rgb2hsl(%v)) = (%d,%d,%d), want ~(%d,%d,%d)", want, r, g, b, wr, wg, wb)
+	}
+}
+
+func absDiff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
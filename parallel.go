@@ -0,0 +1,153 @@
+// Copyright (c) 2012 Guillermo Estrada. All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package blend
+
+import (
+	"image"
+	"image/draw"
+	"runtime"
+	"sync"
+)
+
+// BlendOptions controls how the parallel blending functions split work
+// across goroutines. The zero value blends the whole region serially, as a
+// single row band; use NewBlendOptions to get sensible concurrent defaults.
+type BlendOptions struct {
+	// NumWorkers is the number of goroutines used to process row bands.
+	// A value <= 1 blends the whole region serially, as a single band in a
+	// single goroutine.
+	NumWorkers int
+}
+
+// NewBlendOptions returns BlendOptions tuned to use one worker goroutine
+// per available CPU.
+func NewBlendOptions() BlendOptions {
+	return BlendOptions{NumWorkers: runtime.NumCPU()}
+}
+
+func (o BlendOptions) workers(rows int) int {
+	if o.NumWorkers <= 1 || rows < o.NumWorkers {
+		return 1
+	}
+	return o.NumWorkers
+}
+
+// BlendImageParallel behaves like BlendImage but splits the overlapping
+// region into horizontal row bands and blends them concurrently using
+// runtime.NumCPU() goroutines.
+func BlendImageParallel(dst draw.Image, src image.Image, mode BlendFunc) {
+	blendImage(dst, src, mode, NewBlendOptions())
+}
+
+// BlendNewImageParallel behaves like BlendNewImage but splits the
+// overlapping region into horizontal row bands and blends them
+// concurrently using runtime.NumCPU() goroutines.
+func BlendNewImageParallel(dst, src image.Image, mode BlendFunc) image.Image {
+	return blendNewImage(dst, src, mode, NewBlendOptions())
+}
+
+// blendImage is the shared core behind BlendImage and BlendImageParallel.
+// Each row band is blended by blendRows, which writes straight into dst's
+// backing Pix slice where it can and falls back to dst.Set otherwise.
+func blendImage(dst draw.Image, src image.Image, mode BlendFunc, opts BlendOptions) {
+	inter := dst.Bounds().Intersect(src.Bounds())
+	if inter.Empty() {
+		return
+	}
+
+	band := rowBander(inter, opts)
+	var wg sync.WaitGroup
+	for y0, y1 := inter.Min.Y, 0; y0 < inter.Max.Y; y0 = y1 {
+		y1 = y0 + band
+		if y1 > inter.Max.Y {
+			y1 = inter.Max.Y
+		}
+		wg.Add(1)
+		go func(y0, y1 int) {
+			defer wg.Done()
+			blendRows(dst, src, mode, inter, y0, y1)
+		}(y0, y1)
+	}
+	wg.Wait()
+}
+
+// blendNewImage is the shared core behind BlendNewImage and
+// BlendNewImageParallel.
+func blendNewImage(dst, src image.Image, mode BlendFunc, opts BlendOptions) image.Image {
+	img := image.NewRGBA(dst.Bounds())
+	draw.Draw(img, dst.Bounds(), dst, dst.Bounds().Min, draw.Src)
+
+	inter := dst.Bounds().Intersect(src.Bounds())
+	if inter.Empty() {
+		return img
+	}
+
+	band := rowBander(inter, opts)
+	var wg sync.WaitGroup
+	for y0, y1 := inter.Min.Y, 0; y0 < inter.Max.Y; y0 = y1 {
+		y1 = y0 + band
+		if y1 > inter.Max.Y {
+			y1 = inter.Max.Y
+		}
+		wg.Add(1)
+		go func(y0, y1 int) {
+			defer wg.Done()
+			// img already holds a copy of dst's pixels (seeded above),
+			// so reading d.At(x, y) below yields the same value dst.At
+			// would, while writing goes straight to the new image.
+			blendRows(img, src, mode, inter, y0, y1)
+		}(y0, y1)
+	}
+	wg.Wait()
+	return img
+}
+
+// rowBander returns the number of rows each worker goroutine should
+// process given the intersection rectangle and the requested options.
+func rowBander(inter image.Rectangle, opts BlendOptions) int {
+	rows := inter.Dy()
+	workers := opts.workers(rows)
+	return (rows + workers - 1) / workers
+}
+
+// blendRows applies mode to every pixel in [y0, y1) within inter. It first
+// tries tryKernel's byte-level fast path; failing that, it writes directly
+// into dst's backing Pix slice when dst is *image.RGBA or *image.RGBA64,
+// and falls back to dst.Set for any other draw.Image.
+func blendRows(dst draw.Image, src image.Image, mode BlendFunc, inter image.Rectangle, y0, y1 int) {
+	if tryKernel(dst, src, mode, inter, y0, y1) {
+		return
+	}
+	switch d := dst.(type) {
+	case *image.RGBA:
+		for y := y0; y < y1; y++ {
+			for x := inter.Min.X; x < inter.Max.X; x++ {
+				r, g, b, a := mode(d.At(x, y), src.At(x, y)).RGBA()
+				i := d.PixOffset(x, y)
+				d.Pix[i+0] = uint8(r >> 8)
+				d.Pix[i+1] = uint8(g >> 8)
+				d.Pix[i+2] = uint8(b >> 8)
+				d.Pix[i+3] = uint8(a >> 8)
+			}
+		}
+	case *image.RGBA64:
+		for y := y0; y < y1; y++ {
+			for x := inter.Min.X; x < inter.Max.X; x++ {
+				r, g, b, a := mode(d.At(x, y), src.At(x, y)).RGBA()
+				i := d.PixOffset(x, y)
+				d.Pix[i+0], d.Pix[i+1] = uint8(r>>8), uint8(r)
+				d.Pix[i+2], d.Pix[i+3] = uint8(g>>8), uint8(g)
+				d.Pix[i+4], d.Pix[i+5] = uint8(b>>8), uint8(b)
+				d.Pix[i+6], d.Pix[i+7] = uint8(a>>8), uint8(a)
+			}
+		}
+	default:
+		for y := y0; y < y1; y++ {
+			for x := inter.Min.X; x < inter.Max.X; x++ {
+				dst.Set(x, y, mode(dst.At(x, y), src.At(x, y)))
+			}
+		}
+	}
+}
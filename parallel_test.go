@@ -0,0 +1,49 @@
+package blend
+
+import (
+	"image"
+	"testing"
+)
+
+func benchmarkImages() (*image.RGBA, *image.RGBA) {
+	bounds := image.Rect(0, 0, 3840, 2160)
+	dst := image.NewRGBA(bounds)
+	src := image.NewRGBA(bounds)
+	for i := range dst.Pix {
+		dst.Pix[i] = uint8(i)
+		src.Pix[i] = uint8(i * 7)
+	}
+	return dst, src
+}
+
+func BenchmarkBlendImageSerial(b *testing.B) {
+	dst, src := benchmarkImages()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		BlendImage(dst, src, Overlay)
+	}
+}
+
+func BenchmarkBlendImageParallel(b *testing.B) {
+	dst, src := benchmarkImages()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		BlendImageParallel(dst, src, Overlay)
+	}
+}
+
+func BenchmarkBlendNewImageSerial(b *testing.B) {
+	dst, src := benchmarkImages()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		BlendNewImage(dst, src, Overlay)
+	}
+}
+
+func BenchmarkBlendNewImageParallel(b *testing.B) {
+	dst, src := benchmarkImages()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		BlendNewImageParallel(dst, src, Overlay)
+	}
+}
@@ -0,0 +1,95 @@
+package blend
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// TestStackRenderOpacity is a regression test for renderInto scaling a
+// layer's alpha without its color channels: a 50%-opacity white layer
+// over an opaque black background should land near mid-gray and fully
+// opaque, the same as TestCompositedPartialAlpha checks for Composited
+// directly.
+func TestStackRenderOpacity(t *testing.T) {
+	bounds := image.Rect(0, 0, 1, 1)
+	top := image.NewRGBA(bounds)
+	top.Set(0, 0, color.RGBA{255, 255, 255, 255})
+
+	s := NewStack()
+	layer := NewLayer(top, passThrough)
+	layer.Opacity = 0.5
+	s.AddLayer(layer)
+
+	out := s.Render(bounds)
+	r, g, b, a := out.At(0, 0).RGBA()
+	const want, tol = 0.5 * max, max / 200
+	if math.Abs(float64(r)-want) > tol || math.Abs(float64(g)-want) > tol || math.Abs(float64(b)-want) > tol {
+		t.Fatalf("Render with Opacity=0.5 = (%d,%d,%d,%d), want ~(%.0f,%.0f,%.0f,%d)",
+			r, g, b, a, want, want, want, uint32(max))
+	}
+	if a != max {
+		t.Fatalf("result alpha = %d, want %d (opaque background behind any layer opacity composites opaque)", a, uint32(max))
+	}
+}
+
+// TestStackRenderMask is the Mask counterpart of TestStackRenderOpacity:
+// a fully-opaque white layer behind a 50%-alpha mask should also land at
+// mid-gray, since Layer.At scales the sampled color's alpha by the mask.
+func TestStackRenderMask(t *testing.T) {
+	bounds := image.Rect(0, 0, 1, 1)
+	top := image.NewRGBA(bounds)
+	top.Set(0, 0, color.RGBA{255, 255, 255, 255})
+	mask := image.NewRGBA(bounds)
+	mask.Set(0, 0, color.RGBA{0, 0, 0, 128})
+
+	s := NewStack()
+	layer := NewLayer(top, passThrough)
+	layer.Mask = mask
+	s.AddLayer(layer)
+
+	out := s.Render(bounds)
+	r, g, b, a := out.At(0, 0).RGBA()
+	const want, tol = 0.5 * max, max / 200
+	if math.Abs(float64(r)-want) > tol || math.Abs(float64(g)-want) > tol || math.Abs(float64(b)-want) > tol {
+		t.Fatalf("Render with a 50%% mask = (%d,%d,%d,%d), want ~(%.0f,%.0f,%.0f,%d)",
+			r, g, b, a, want, want, want, uint32(max))
+	}
+	if a != max {
+		t.Fatalf("result alpha = %d, want %d (opaque background behind any mask composites opaque)", a, uint32(max))
+	}
+}
+
+// TestStackRenderParallelMatchesRender checks that tiling a render across
+// goroutines produces the same pixels as the serial path, for a stack
+// with a masked, partially-opaque layer — the case renderInto's
+// scaleAlpha fix and RenderParallel's tiling both need to agree on.
+func TestStackRenderParallelMatchesRender(t *testing.T) {
+	bounds := image.Rect(0, 0, 130, 130)
+	top := image.NewRGBA(bounds)
+	mask := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			top.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, uint8(x + y)})
+			mask.Set(x, y, color.RGBA{0, 0, 0, uint8(x * y)})
+		}
+	}
+
+	s := NewStack()
+	layer := NewLayer(top, Multiply)
+	layer.Opacity = 0.75
+	layer.Mask = mask
+	s.AddLayer(layer)
+
+	serial := s.Render(bounds)
+	parallel := s.RenderParallel(bounds)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if serial.At(x, y) != parallel.At(x, y) {
+				t.Fatalf("Render/RenderParallel mismatch at (%d,%d): %v vs %v", x, y, serial.At(x, y), parallel.At(x, y))
+			}
+		}
+	}
+}